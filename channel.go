@@ -1,8 +1,10 @@
 package pool
 
 import (
+	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,10 +18,20 @@ type Config struct {
 	Factory func() (interface{}, error)
 	//关闭连接的方法
 	Close func(interface{}) error
-	//探活方法
-	Alive func() error
+	//探活方法，接收具体的连接以便针对该连接发起检测
+	Ping func(interface{}) error
+	//是否在每次Get时都对取出的连接执行Ping，关闭可降低延迟敏感场景的开销
+	TestOnBorrow bool
 	//最大空闲时间
 	IdleTimeout time.Duration
+	//后台扫描空闲连接的间隔，为0则不启动后台扫描协程
+	IdleCheckFrequency time.Duration
+	//后台扫描时需要保持的最小空闲连接数，为0则不补充
+	MinIdle int
+	//连接最大存活时间，从创建时起计算，超过后Get会淘汰并换一个新连接，0表示不限制
+	MaxLifetime time.Duration
+	//连接最大被借出次数，超过后Get会淘汰并换一个新连接，0表示不限制
+	MaxUsage int
 }
 type channelPool struct {
 	//锁
@@ -31,9 +43,29 @@ type channelPool struct {
 	//关闭连接的方法
 	close func(interface{}) error
 	//探活方法
-	alive func() error
+	ping func(interface{}) error
+	//是否在Get时对取出的连接执行Ping
+	testOnBorrow bool
 	//最大空闲时间
 	idleTimeout time.Duration
+	//最大连接数，Get在达到该上限时阻塞而不是无限新建连接
+	maxCap int
+	//后台扫描空闲连接的间隔
+	idleCheckFrequency time.Duration
+	//后台扫描时需要保持的最小空闲连接数
+	minIdle int
+	//用于通知后台扫描协程退出
+	done chan struct{}
+	//等待后台扫描协程彻底退出后，Release才能关闭conns
+	reaperWG sync.WaitGroup
+	//连接最大存活时间
+	maxLifetime time.Duration
+	//连接最大被借出次数
+	maxUsage int
+	//conn -> *connMeta，记录每个连接创建时间和被借出次数，跨越Put/Get周期持续存在
+	connMeta sync.Map
+	//运行时统计计数器，只能通过atomic操作访问
+	hits, misses, timeouts, totalConns, staleConns uint32
 }
 type idleConn struct {
 	//真正的连接
@@ -42,15 +74,59 @@ type idleConn struct {
 	t time.Time
 }
 
+//connMeta 记录一个连接从创建起的存活时间和被借出次数，用于MaxLifetime/MaxUsage淘汰
+type connMeta struct {
+	//创建时间
+	createdAt time.Time
+	//被借出的次数
+	useCount int
+}
+
+//registerConn 为新创建的连接建立生命周期元数据
+func (cPool *channelPool) registerConn(conn interface{}) {
+	cPool.connMeta.Store(conn, &connMeta{createdAt: time.Now()})
+}
+
+//tryReserveConn 原子地为一个即将创建的新连接预占名额，成功后调用方必须创建连接，
+//创建失败时需调用releaseConn回滚，避免并发Get在totalConns的check-then-act间隙一起越过MaxCap
+func (cPool *channelPool) tryReserveConn() bool {
+	for {
+		cur := atomic.LoadUint32(&cPool.totalConns)
+		if cur >= uint32(cPool.maxCap) {
+			return false
+		}
+		if atomic.CompareAndSwapUint32(&cPool.totalConns, cur, cur+1) {
+			return true
+		}
+	}
+}
+
+//releaseConn 回滚tryReserveConn预占的名额
+func (cPool *channelPool) releaseConn() {
+	atomic.AddUint32(&cPool.totalConns, ^uint32(0))
+}
+
+//forgetConn 在连接被永久关闭时清理其生命周期元数据
+func (cPool *channelPool) forgetConn(conn interface{}) {
+	cPool.connMeta.Delete(conn)
+}
+
 func NewChannelPool(poolConfig *Config) (Pool, error) {
 	if poolConfig.InitCap <= 0 || poolConfig.MaxCap <= 0 || poolConfig.InitCap > poolConfig.MaxCap {
 		return nil, errors.New("poolConfig.InitCap or poolConfig.MaxCap is error")
 	}
 	cPool := &channelPool{
-		conns:       make(chan *idleConn, poolConfig.MaxCap),
-		factory:     poolConfig.Factory,
-		close:       poolConfig.Close,
-		idleTimeout: poolConfig.IdleTimeout,
+		conns:              make(chan *idleConn, poolConfig.MaxCap),
+		factory:            poolConfig.Factory,
+		close:              poolConfig.Close,
+		ping:               poolConfig.Ping,
+		testOnBorrow:       poolConfig.TestOnBorrow,
+		idleTimeout:        poolConfig.IdleTimeout,
+		maxCap:             poolConfig.MaxCap,
+		idleCheckFrequency: poolConfig.IdleCheckFrequency,
+		minIdle:            poolConfig.MinIdle,
+		maxLifetime:        poolConfig.MaxLifetime,
+		maxUsage:           poolConfig.MaxUsage,
 	}
 	for i := 0; i < poolConfig.InitCap; i++ {
 		conn, err := cPool.factory()
@@ -58,7 +134,14 @@ func NewChannelPool(poolConfig *Config) (Pool, error) {
 			cPool.Release()
 			return nil, err
 		}
+		cPool.registerConn(conn)
 		cPool.conns <- &idleConn{conn: conn, t: time.Now()}
+		atomic.AddUint32(&cPool.totalConns, 1)
+	}
+	if cPool.idleCheckFrequency > 0 {
+		cPool.done = make(chan struct{})
+		cPool.reaperWG.Add(1)
+		go cPool.startReaper(cPool.done)
 	}
 	return cPool, nil
 }
@@ -70,34 +153,161 @@ func (cPool *channelPool) getConns() chan *idleConn {
 	return conns
 }
 func (cPool *channelPool) Get() (interface{}, error) {
-	conns := cPool.getConns()
-	if conns == nil {
-		return nil, ErrClose
-	}
+	return cPool.GetContext(context.Background())
+}
+
+//GetContext 与Get类似，但当连接总数已达到MaxCap时会阻塞等待，
+//直到有连接被Put归还或ctx被取消/超时，此时Timeouts计数加一
+func (cPool *channelPool) GetContext(ctx context.Context) (interface{}, error) {
 	for {
+		conns := cPool.getConns()
+		if conns == nil {
+			return nil, ErrClose
+		}
 		select {
 		case wrapConn := <-conns:
 			if wrapConn == nil {
 				return nil, ErrClose
 			}
-			//判断是否超时
-			if timeOut := cPool.idleTimeout; timeOut > 0 {
-				if wrapConn.t.Add(timeOut).Before(time.Now()) {
-					cPool.close(wrapConn)
-					continue
-				}
+			if conn, ok := cPool.takeConn(wrapConn); ok {
+				return conn, nil
 			}
-			return wrapConn.conn, nil
+			continue
 		default:
-			conn, err := cPool.factory()
-			if err != nil {
-				return nil, err
+			if cPool.tryReserveConn() {
+				conn, err := cPool.factory()
+				if err != nil {
+					cPool.releaseConn()
+					return nil, err
+				}
+				//新建的连接直接被借出，本次借出也计入useCount
+				cPool.connMeta.Store(conn, &connMeta{createdAt: time.Now(), useCount: 1})
+				atomic.AddUint32(&cPool.misses, 1)
+				return conn, nil
+			}
+			//已达到MaxCap，等待连接被归还或ctx被取消
+			select {
+			case wrapConn := <-conns:
+				if wrapConn == nil {
+					return nil, ErrClose
+				}
+				if conn, ok := cPool.takeConn(wrapConn); ok {
+					return conn, nil
+				}
+				continue
+			case <-ctx.Done():
+				atomic.AddUint32(&cPool.timeouts, 1)
+				return nil, ctx.Err()
 			}
-			return conn, nil
 		}
+	}
+}
 
+//takeConn 校验一个从池中取出的连接是否仍然有效，无效则关闭并返回false
+func (cPool *channelPool) takeConn(wrapConn *idleConn) (interface{}, bool) {
+	//判断是否超时
+	if timeOut := cPool.idleTimeout; timeOut > 0 {
+		if wrapConn.t.Add(timeOut).Before(time.Now()) {
+			cPool.close(wrapConn.conn)
+			cPool.forgetConn(wrapConn.conn)
+			atomic.AddUint32(&cPool.staleConns, 1)
+			atomic.AddUint32(&cPool.totalConns, ^uint32(0))
+			return nil, false
+		}
 	}
+	//按创建时间/使用次数淘汰连接，对应database/sql的SetConnMaxLifetime语义
+	if meta, ok := cPool.connMeta.Load(wrapConn.conn); ok {
+		m := meta.(*connMeta)
+		if maxLifetime := cPool.maxLifetime; maxLifetime > 0 && time.Since(m.createdAt) >= maxLifetime {
+			cPool.close(wrapConn.conn)
+			cPool.forgetConn(wrapConn.conn)
+			atomic.AddUint32(&cPool.totalConns, ^uint32(0))
+			return nil, false
+		}
+		if maxUsage := cPool.maxUsage; maxUsage > 0 && m.useCount >= maxUsage {
+			cPool.close(wrapConn.conn)
+			cPool.forgetConn(wrapConn.conn)
+			atomic.AddUint32(&cPool.totalConns, ^uint32(0))
+			return nil, false
+		}
+		m.useCount++
+	}
+	//借出前探活，失败则关闭该连接，由调用方的循环重新获取一个新连接
+	if cPool.testOnBorrow && cPool.ping != nil {
+		if err := cPool.ping(wrapConn.conn); err != nil {
+			cPool.close(wrapConn.conn)
+			cPool.forgetConn(wrapConn.conn)
+			atomic.AddUint32(&cPool.totalConns, ^uint32(0))
+			return nil, false
+		}
+	}
+	atomic.AddUint32(&cPool.hits, 1)
+	return wrapConn.conn, true
+}
 
+//startReaper 按IdleCheckFrequency周期扫描空闲连接，直到done被关闭。
+//done以参数形式传入并在本地持有，避免与Release()把cPool.done置nil产生数据竞争
+func (cPool *channelPool) startReaper(done chan struct{}) {
+	defer cPool.reaperWG.Done()
+	ticker := time.NewTicker(cPool.idleCheckFrequency)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			cPool.reapIdle()
+		case <-done:
+			return
+		}
+	}
+}
+
+//reapIdle 关闭已超过IdleTimeout的空闲连接，并在设置了MinIdle时补充到最小空闲数
+func (cPool *channelPool) reapIdle() {
+	conns := cPool.getConns()
+	if conns == nil {
+		return
+	}
+	n := len(conns)
+	for i := 0; i < n; i++ {
+		select {
+		case wrapConn := <-conns:
+			if wrapConn == nil {
+				return
+			}
+			if timeOut := cPool.idleTimeout; timeOut > 0 && wrapConn.t.Add(timeOut).Before(time.Now()) {
+				cPool.close(wrapConn.conn)
+				cPool.forgetConn(wrapConn.conn)
+				atomic.AddUint32(&cPool.staleConns, 1)
+				atomic.AddUint32(&cPool.totalConns, ^uint32(0))
+				continue
+			}
+			select {
+			case conns <- wrapConn:
+			default:
+				cPool.close(wrapConn.conn)
+				cPool.forgetConn(wrapConn.conn)
+				atomic.AddUint32(&cPool.totalConns, ^uint32(0))
+			}
+		default:
+			return
+		}
+	}
+	for cPool.minIdle > 0 && len(conns) < cPool.minIdle && cPool.tryReserveConn() {
+		conn, err := cPool.factory()
+		if err != nil {
+			cPool.releaseConn()
+			return
+		}
+		cPool.registerConn(conn)
+		select {
+		case conns <- &idleConn{conn: conn, t: time.Now()}:
+		default:
+			cPool.close(conn)
+			cPool.forgetConn(conn)
+			cPool.releaseConn()
+			return
+		}
+	}
 }
 
 func (cPool *channelPool) Put(conn interface{}) error {
@@ -113,10 +323,21 @@ func (cPool *channelPool) Put(conn interface{}) error {
 		return cPool.Close(conn)
 	}
 
+	//归还的连接已不健康，直接丢弃而不是放回池中
+	if cPool.ping != nil {
+		if err := cPool.ping(conn); err != nil {
+			cPool.forgetConn(conn)
+			atomic.AddUint32(&cPool.totalConns, ^uint32(0))
+			return cPool.Close(conn)
+		}
+	}
+
 	select {
 	case cPool.conns <- &idleConn{conn: conn, t: time.Now()}:
 		return nil
 	default:
+		cPool.forgetConn(conn)
+		atomic.AddUint32(&cPool.totalConns, ^uint32(0))
 		return cPool.Close(conn)
 	}
 }
@@ -131,6 +352,19 @@ func (cPool *channelPool) Release() {
 	cPool.mu.Lock()
 	conns := cPool.conns
 	closeFunc := cPool.close
+	done := cPool.done
+	cPool.done = nil
+	cPool.mu.Unlock()
+
+	if done != nil {
+		close(done)
+		//等待后台扫描协程彻底退出：reapIdle会在不持锁的情况下读取cPool.factory/cPool.close，
+		//必须先让它完全退出（reaperWG.Wait()与其Done()构成happens-before），
+		//再去置空这些字段，否则会和reapIdle的并发读形成数据竞争
+		cPool.reaperWG.Wait()
+	}
+
+	cPool.mu.Lock()
 	cPool.conns = nil
 	cPool.close = nil
 	cPool.factory = nil
@@ -138,9 +372,20 @@ func (cPool *channelPool) Release() {
 
 	close(conns)
 	for wrapConn := range conns {
-		closeFunc(wrapConn)
+		closeFunc(wrapConn.conn)
 	}
 }
 func (cPool *channelPool) Len() int {
 	return len(cPool.getConns())
 }
+
+func (cPool *channelPool) Stats() Stats {
+	return Stats{
+		Hits:       atomic.LoadUint32(&cPool.hits),
+		Misses:     atomic.LoadUint32(&cPool.misses),
+		Timeouts:   atomic.LoadUint32(&cPool.timeouts),
+		TotalConns: atomic.LoadUint32(&cPool.totalConns),
+		IdleConns:  uint32(cPool.Len()),
+		StaleConns: atomic.LoadUint32(&cPool.staleConns),
+	}
+}
@@ -0,0 +1,275 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestIntPool(t *testing.T, initCap, maxCap int) Pool {
+	t.Helper()
+	p, err := NewChannelPool(&Config{
+		InitCap: initCap,
+		MaxCap:  maxCap,
+		Factory: func() (interface{}, error) { return new(int), nil },
+		Close:   func(interface{}) error { return nil },
+	})
+	if err != nil {
+		t.Fatalf("NewChannelPool: %v", err)
+	}
+	return p
+}
+
+func TestStatsHitsAndMisses(t *testing.T) {
+	p := newTestIntPool(t, 1, 2)
+	defer p.Release()
+
+	//InitCap=1，第一次Get应命中已有的空闲连接
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := p.Stats(); s.Hits != 1 || s.Misses != 0 {
+		t.Fatalf("got Hits=%d Misses=%d, want Hits=1 Misses=0", s.Hits, s.Misses)
+	}
+
+	//池已空，第二次Get应触发新建（miss）
+	if _, err := p.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if s := p.Stats(); s.Hits != 1 || s.Misses != 1 || s.TotalConns != 2 {
+		t.Fatalf("got %+v, want Hits=1 Misses=1 TotalConns=2", p.Stats())
+	}
+
+	if err := p.Put(conn); err != nil {
+		t.Fatal(err)
+	}
+	if s := p.Stats(); s.IdleConns != 1 {
+		t.Fatalf("got IdleConns=%d, want 1", s.IdleConns)
+	}
+}
+
+func TestGetContextBlocksAtMaxCapThenUnblocksOnPut(t *testing.T) {
+	p := newTestIntPool(t, 1, 1)
+	defer p.Release()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if _, err := p.GetContext(ctx); err != nil {
+			t.Error(err)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("GetContext returned before the only connection was put back")
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	if err := p.Put(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("GetContext never unblocked after Put")
+	}
+}
+
+func TestGetContextCancellation(t *testing.T) {
+	p := newTestIntPool(t, 1, 1)
+	defer p.Release()
+
+	if _, err := p.Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := p.GetContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("got err=%v, want context.DeadlineExceeded", err)
+	}
+	if s := p.Stats(); s.Timeouts != 1 {
+		t.Fatalf("got Timeouts=%d, want 1", s.Timeouts)
+	}
+}
+
+func TestMaxCapStrictUnderConcurrency(t *testing.T) {
+	p, err := NewChannelPool(&Config{
+		InitCap: 1,
+		MaxCap:  2,
+		Factory: func() (interface{}, error) {
+			time.Sleep(10 * time.Millisecond)
+			return new(int), nil
+		},
+		Close: func(interface{}) error { return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Release()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			p.GetContext(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if s := p.Stats(); s.TotalConns > 2 {
+		t.Fatalf("TotalConns=%d, want <= MaxCap(2)", s.TotalConns)
+	}
+}
+
+func TestTestOnBorrowEvictsUnhealthyConn(t *testing.T) {
+	var pingCalls int32
+	bad := new(int)
+	var created int32
+	p, err := NewChannelPool(&Config{
+		InitCap: 1,
+		MaxCap:  1,
+		Factory: func() (interface{}, error) {
+			created++
+			if created == 1 {
+				return bad, nil
+			}
+			return new(int), nil
+		},
+		Close: func(interface{}) error { return nil },
+		Ping: func(conn interface{}) error {
+			pingCalls++
+			if conn == bad {
+				return errors.New("unhealthy")
+			}
+			return nil
+		},
+		TestOnBorrow: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Release()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn == bad {
+		t.Fatal("Get returned the unhealthy connection instead of evicting it and creating a replacement")
+	}
+	if pingCalls == 0 {
+		t.Fatal("Ping was never called on borrow")
+	}
+	if s := p.Stats(); s.TotalConns != 1 {
+		t.Fatalf("TotalConns=%d, want 1 (bad conn evicted, replacement created)", s.TotalConns)
+	}
+}
+
+func TestPutDropsUnhealthyConn(t *testing.T) {
+	var closed int32
+	var pingErr error
+	p, err := NewChannelPool(&Config{
+		InitCap: 1,
+		MaxCap:  1,
+		Factory: func() (interface{}, error) { return new(int), nil },
+		Close: func(interface{}) error {
+			closed++
+			return nil
+		},
+		Ping: func(interface{}) error { return pingErr },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Release()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pingErr = errors.New("connection is dead")
+	if err := p.Put(conn); err != nil {
+		t.Fatal(err)
+	}
+	if closed == 0 {
+		t.Fatal("Put should have closed the unhealthy connection instead of returning it to the pool")
+	}
+	if s := p.Stats(); s.IdleConns != 0 {
+		t.Fatalf("IdleConns=%d, want 0 (unhealthy conn must not be put back)", s.IdleConns)
+	}
+}
+
+func TestReaperEvictsStaleAndRefillsMinIdle(t *testing.T) {
+	var created int32
+	p, err := NewChannelPool(&Config{
+		InitCap: 2,
+		MaxCap:  2,
+		Factory: func() (interface{}, error) {
+			atomic.AddInt32(&created, 1)
+			return new(int), nil
+		},
+		Close:              func(interface{}) error { return nil },
+		IdleTimeout:        20 * time.Millisecond,
+		IdleCheckFrequency: 10 * time.Millisecond,
+		MinIdle:            1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Release()
+
+	if s := p.Stats(); s.IdleConns != 2 {
+		t.Fatalf("IdleConns=%d, want 2 before reaping", s.IdleConns)
+	}
+
+	//两个空闲连接应在IdleTimeout之后被后台扫描协程回收，
+	//但由于设置了MinIdle=1，扫描协程会补充出一个新连接
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if s := p.Stats(); s.StaleConns >= 2 && s.IdleConns == 1 {
+			if n := atomic.LoadInt32(&created); n < 3 {
+				t.Fatalf("created=%d, want 3 (2 initial + a replacement connection created for MinIdle)", n)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("reaper never evicted stale conns and refilled to MinIdle, got %+v", p.Stats())
+}
+
+func TestReleaseStopsReaperCleanlyWithoutPanic(t *testing.T) {
+	p, err := NewChannelPool(&Config{
+		InitCap:            1,
+		MaxCap:             2,
+		Factory:            func() (interface{}, error) { return new(int), nil },
+		Close:              func(interface{}) error { return nil },
+		IdleTimeout:        5 * time.Millisecond,
+		IdleCheckFrequency: time.Millisecond,
+		MinIdle:            1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//故意在后台扫描协程大概率正忙着回收/补充连接时Release，
+	//验证Release会先停掉reaper再关闭conns，不会出现向已关闭channel发送而panic
+	time.Sleep(3 * time.Millisecond)
+	p.Release()
+}
@@ -0,0 +1,164 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	//ErrUnknownKey 在key对应的子池尚未创建或已被Release时返回
+	ErrUnknownKey = errors.New("pool: unknown key")
+)
+
+//MultiFactory 按key生成连接的方法，用于MultiPool区分不同的后端地址/分片
+type MultiFactory[K comparable] func(key K) (interface{}, error)
+
+//MultiConfig MultiPool配置，除Factory外其余字段与Config含义一致，应用于每个子池
+type MultiConfig[K comparable] struct {
+	//每个子池的最小连接数
+	InitCap int
+	//每个子池的最大连接数，该上限按key分别独立生效，
+	//不是跨所有key共享的全局预算——N个key最多可同时存在 N*MaxCap 个连接
+	MaxCap int
+	//按key生成连接的方法
+	Factory MultiFactory[K]
+	//关闭连接的方法
+	Close func(interface{}) error
+	//探活方法
+	Ping func(interface{}) error
+	//是否在每次Get时都对取出的连接执行Ping
+	TestOnBorrow bool
+	//最大空闲时间
+	IdleTimeout time.Duration
+	//后台扫描空闲连接的间隔，为0则不启动后台扫描协程
+	IdleCheckFrequency time.Duration
+	//后台扫描时需要保持的最小空闲连接数
+	MinIdle int
+}
+
+//MultiPool 按key对多个后端地址/分片分别维护一个channelPool，
+//每个子池各自按MultiConfig的InitCap/MaxCap独立建池（MaxCap不是跨key共享的全局上限），
+//懒加载、首次Get时才会创建对应子池。
+//K要求是comparable，这样用不可比较的类型（slice/map/func）当key在编译期就会报错，
+//而不是等到map索引时才panic
+type MultiPool[K comparable] struct {
+	//锁，保护pools
+	mu sync.RWMutex
+	//key到子池的映射
+	pools map[K]Pool
+	//每个子池共用的配置
+	config *MultiConfig[K]
+}
+
+//NewMultiPool 创建一个按key分片的连接池，config对所有子池生效
+func NewMultiPool[K comparable](config *MultiConfig[K]) *MultiPool[K] {
+	return &MultiPool[K]{
+		pools:  make(map[K]Pool),
+		config: config,
+	}
+}
+
+func (mp *MultiPool[K]) getOrCreate(key K) (Pool, error) {
+	mp.mu.RLock()
+	p, ok := mp.pools[key]
+	mp.mu.RUnlock()
+	if ok {
+		return p, nil
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	if p, ok := mp.pools[key]; ok {
+		return p, nil
+	}
+	p, err := NewChannelPool(&Config{
+		InitCap: mp.config.InitCap,
+		MaxCap:  mp.config.MaxCap,
+		Factory: func() (interface{}, error) {
+			return mp.config.Factory(key)
+		},
+		Close:              mp.config.Close,
+		Ping:               mp.config.Ping,
+		TestOnBorrow:       mp.config.TestOnBorrow,
+		IdleTimeout:        mp.config.IdleTimeout,
+		IdleCheckFrequency: mp.config.IdleCheckFrequency,
+		MinIdle:            mp.config.MinIdle,
+	})
+	if err != nil {
+		return nil, err
+	}
+	mp.pools[key] = p
+	return p, nil
+}
+
+//Get 从key对应的子池获取一个连接，子池不存在时会按MultiConfig懒创建
+func (mp *MultiPool[K]) Get(key K) (interface{}, error) {
+	return mp.GetContext(context.Background(), key)
+}
+
+//GetContext 与Get类似，但在子池达到MaxCap时按ctx阻塞等待
+func (mp *MultiPool[K]) GetContext(ctx context.Context, key K) (interface{}, error) {
+	p, err := mp.getOrCreate(key)
+	if err != nil {
+		return nil, err
+	}
+	return p.GetContext(ctx)
+}
+
+//Put 将连接归还给key对应的子池
+func (mp *MultiPool[K]) Put(key K, conn interface{}) error {
+	mp.mu.RLock()
+	p, ok := mp.pools[key]
+	mp.mu.RUnlock()
+	if !ok {
+		return ErrUnknownKey
+	}
+	return p.Put(conn)
+}
+
+//Len 返回key对应子池当前的空闲连接数，子池不存在时返回0
+func (mp *MultiPool[K]) Len(key K) int {
+	mp.mu.RLock()
+	p, ok := mp.pools[key]
+	mp.mu.RUnlock()
+	if !ok {
+		return 0
+	}
+	return p.Len()
+}
+
+//Stats 返回key对应子池的运行时统计信息，子池不存在时返回零值
+func (mp *MultiPool[K]) Stats(key K) Stats {
+	mp.mu.RLock()
+	p, ok := mp.pools[key]
+	mp.mu.RUnlock()
+	if !ok {
+		return Stats{}
+	}
+	return p.Stats()
+}
+
+//Release 释放并移除key对应的子池
+func (mp *MultiPool[K]) Release(key K) {
+	mp.mu.Lock()
+	p, ok := mp.pools[key]
+	delete(mp.pools, key)
+	mp.mu.Unlock()
+	if ok {
+		p.Release()
+	}
+}
+
+//ReleaseAll 释放所有子池
+func (mp *MultiPool[K]) ReleaseAll() {
+	mp.mu.Lock()
+	pools := mp.pools
+	mp.pools = make(map[K]Pool)
+	mp.mu.Unlock()
+
+	for _, p := range pools {
+		p.Release()
+	}
+}
@@ -0,0 +1,86 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestMultiIntPool(t *testing.T, maxCap int) *MultiPool[string] {
+	t.Helper()
+	return NewMultiPool(&MultiConfig[string]{
+		InitCap: 1,
+		MaxCap:  maxCap,
+		Factory: func(key string) (interface{}, error) {
+			v := key
+			return &v, nil
+		},
+		Close: func(interface{}) error { return nil },
+	})
+}
+
+func TestMultiPoolPerKeyIsolation(t *testing.T) {
+	mp := newTestMultiIntPool(t, 1)
+	defer mp.ReleaseAll()
+
+	connA, err := mp.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := *(connA.(*string)); got != "a" {
+		t.Fatalf("key %q returned a connection built for %q", "a", got)
+	}
+
+	connB, err := mp.Get("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := *(connB.(*string)); got != "b" {
+		t.Fatalf("key %q returned a connection built for %q", "b", got)
+	}
+
+	//key"a"已达MaxCap=1，但这不应影响key"b"：每个key各自独立计量
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := mp.GetContext(ctx, "a"); err != context.DeadlineExceeded {
+		t.Fatalf("key %q: got err=%v, want context.DeadlineExceeded (MaxCap reached)", "a", err)
+	}
+
+	if sA := mp.Stats("a"); sA.TotalConns != 1 {
+		t.Fatalf("key %q TotalConns=%d, want 1", "a", sA.TotalConns)
+	}
+	if sB := mp.Stats("b"); sB.TotalConns != 1 {
+		t.Fatalf("key %q TotalConns=%d, want 1", "b", sB.TotalConns)
+	}
+
+	if err := mp.Put("a", connA); err != nil {
+		t.Fatal(err)
+	}
+	if err := mp.Put("b", connB); err != nil {
+		t.Fatal(err)
+	}
+	if mp.Len("a") != 1 || mp.Len("b") != 1 {
+		t.Fatalf("got Len(a)=%d Len(b)=%d, want 1 and 1", mp.Len("a"), mp.Len("b"))
+	}
+}
+
+func TestMultiPoolReleaseOnlyAffectsItsKey(t *testing.T) {
+	mp := newTestMultiIntPool(t, 1)
+	defer mp.ReleaseAll()
+
+	if _, err := mp.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mp.Get("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	mp.Release("a")
+
+	if err := mp.Put("a", new(string)); err != ErrUnknownKey {
+		t.Fatalf("got err=%v, want ErrUnknownKey after releasing key %q", err, "a")
+	}
+	if sB := mp.Stats("b"); sB.TotalConns != 1 {
+		t.Fatalf("releasing key %q should not affect key %q, got TotalConns=%d", "a", "b", sB.TotalConns)
+	}
+}
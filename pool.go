@@ -1,6 +1,9 @@
 package pool
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 var (
 	ErrClose = errors.New("pool is closed")
@@ -9,8 +12,27 @@ var (
 //Pool 连接池接口
 type Pool interface {
 	Get() (interface{}, error)
+	GetContext(ctx context.Context) (interface{}, error)
+	GetWrapped() (*PooledConn, error)
 	Put(interface{}) error
 	Close(interface{}) error
 	Release()
 	Len() int
+	Stats() Stats
+}
+
+//Stats 连接池运行时统计信息，用于监控池的命中率和容量使用情况
+type Stats struct {
+	//Get命中空闲连接的次数
+	Hits uint32
+	//Get时连接池为空、需要新建连接的次数
+	Misses uint32
+	//等待连接超时的次数
+	Timeouts uint32
+	//当前池内连接总数（空闲+已借出）
+	TotalConns uint32
+	//当前空闲连接数
+	IdleConns uint32
+	//因超过IdleTimeout被回收的连接数
+	StaleConns uint32
 }
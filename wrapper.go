@@ -0,0 +1,87 @@
+package pool
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+)
+
+//ErrDiscarded 在一个已经被Close或Discard过的PooledConn上再次操作时返回
+var ErrDiscarded = errors.New("pool: connection already closed or discarded")
+
+//PooledConn 包装一个从Pool借出的连接，Close时会把连接归还给来源的Pool而不是真正关闭它，
+//从而省去调用方手写defer p.Put(conn)的样板代码
+type PooledConn struct {
+	//实际的连接
+	Conn interface{}
+	//来源的连接池
+	pool Pool
+	//连接是否已被归还或丢弃，CompareAndSwap保证并发Close/Discard只有一次生效
+	unusable atomic.Bool
+}
+
+//GetWrapped 与Get类似，但返回的PooledConn的Close方法会自动把连接归还给池
+func (cPool *channelPool) GetWrapped() (*PooledConn, error) {
+	conn, err := cPool.Get()
+	if err != nil {
+		return nil, err
+	}
+	return &PooledConn{Conn: conn, pool: cPool}, nil
+}
+
+//Close 把连接归还给来源的池；重复调用、或与Discard并发调用，都只有一方真正生效
+func (p *PooledConn) Close() error {
+	if !p.unusable.CompareAndSwap(false, true) {
+		return nil
+	}
+	return p.pool.Put(p.Conn)
+}
+
+//Discard 强制关闭连接而不归还给池，用于连接在协议层面已不可用（如读到异常响应）的场景
+func (p *PooledConn) Discard() error {
+	if !p.unusable.CompareAndSwap(false, true) {
+		return nil
+	}
+	discardFromChannelPool(p.pool, p.Conn)
+	return p.pool.Close(p.Conn)
+}
+
+//PooledNetConn 在net.Conn之上重新暴露net.Conn接口，使池化的TCP连接可以直接替换原生net.Conn使用，
+//Close时连接被归还给池，需要彻底关闭时改用Discard
+type PooledNetConn struct {
+	net.Conn
+	pool Pool
+	//连接是否已被归还或丢弃，CompareAndSwap保证并发Close/Discard只有一次生效
+	unusable atomic.Bool
+}
+
+//NewPooledNetConn 用给定的池和net.Conn构造一个PooledNetConn，通常在factory/Get之后由调用方装配
+func NewPooledNetConn(pool Pool, conn net.Conn) *PooledNetConn {
+	return &PooledNetConn{Conn: conn, pool: pool}
+}
+
+//Close 把底层连接归还给来源的池；重复调用、或与Discard并发调用，都只有一方真正生效
+func (p *PooledNetConn) Close() error {
+	if !p.unusable.CompareAndSwap(false, true) {
+		return nil
+	}
+	return p.pool.Put(p.Conn)
+}
+
+//Discard 强制关闭底层连接而不归还给池
+func (p *PooledNetConn) Discard() error {
+	if !p.unusable.CompareAndSwap(false, true) {
+		return nil
+	}
+	discardFromChannelPool(p.pool, p.Conn)
+	return p.pool.Close(p.Conn)
+}
+
+//discardFromChannelPool 在连接被Discard时把它从channelPool的TotalConns和生命周期元数据中移除，
+//使被丢弃的连接不再占用MaxCap名额
+func discardFromChannelPool(pool Pool, conn interface{}) {
+	if cPool, ok := pool.(*channelPool); ok {
+		cPool.forgetConn(conn)
+		cPool.releaseConn()
+	}
+}
@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPooledConnCloseDiscardOnlyTakesEffectOnce(t *testing.T) {
+	var puts, closes int32
+	fakePool := &countingPool{
+		put:   func(interface{}) error { atomic.AddInt32(&puts, 1); return nil },
+		close: func(interface{}) error { atomic.AddInt32(&closes, 1); return nil },
+	}
+	pc := &PooledConn{Conn: new(int), pool: fakePool}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() { defer wg.Done(); pc.Close() }()
+	}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() { defer wg.Done(); pc.Discard() }()
+	}
+	wg.Wait()
+
+	//Close归还给池，Discard真正关闭连接，二者互斥——无论并发调用多少次，
+	//Put+Close加起来应当只真正生效一次
+	if got := atomic.LoadInt32(&puts) + atomic.LoadInt32(&closes); got != 1 {
+		t.Fatalf("Put+Close called %d times total, want exactly 1", got)
+	}
+}
+
+func TestPooledNetConnCloseDiscardExactlyOnce(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	var puts, closes int32
+	fakePool := &countingPool{
+		put:   func(interface{}) error { atomic.AddInt32(&puts, 1); return nil },
+		close: func(interface{}) error { atomic.AddInt32(&closes, 1); return client.Close() },
+	}
+	pc := NewPooledNetConn(fakePool, client)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() { defer wg.Done(); pc.Close() }()
+	}
+	wg.Wait()
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() { defer wg.Done(); pc.Discard() }()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&puts) + atomic.LoadInt32(&closes); got != 1 {
+		t.Fatalf("Put+Close called %d times total, want exactly 1 (first caller wins, Discard after Close is a no-op)", got)
+	}
+}
+
+//countingPool是一个最小的Pool实现，仅用于验证PooledConn/PooledNetConn的Close/Discard是否真正只生效一次
+type countingPool struct {
+	put   func(interface{}) error
+	close func(interface{}) error
+}
+
+func (c *countingPool) Get() (interface{}, error) { return nil, nil }
+func (c *countingPool) GetContext(ctx context.Context) (interface{}, error) {
+	return nil, nil
+}
+func (c *countingPool) GetWrapped() (*PooledConn, error) { return nil, nil }
+func (c *countingPool) Put(conn interface{}) error       { return c.put(conn) }
+func (c *countingPool) Close(conn interface{}) error     { return c.close(conn) }
+func (c *countingPool) Release()                         {}
+func (c *countingPool) Len() int                         { return 0 }
+func (c *countingPool) Stats() Stats                     { return Stats{} }